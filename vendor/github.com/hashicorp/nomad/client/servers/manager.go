@@ -0,0 +1,318 @@
+// Package servers provides an interface for choosing which Nomad server a
+// client's RPCs should be routed to. It replaces the "shuffle the whole
+// list and pick one" approach with a manager that remembers which servers
+// are healthy, rotates away from ones that fail, and periodically
+// rebalances so that load spreads across the cluster rather than pinning
+// to whichever server happened to answer first.
+package servers
+
+import (
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// clientRPCMinReuseDuration is the minimum amount of time RPCs must be
+	// sent to the same server before a rebalance is allowed to move on to
+	// a new one.
+	clientRPCMinReuseDuration = 5 * time.Minute
+
+	// numServersRebalanceThreshold is the number of servers a cluster must
+	// exceed before the rebalance period is scaled by
+	// rebalancePerServerInterval instead of just rebalancing every
+	// clientRPCMinReuseDuration.
+	numServersRebalanceThreshold = 3
+
+	// rebalancePerServerInterval is the minimum amount of time a client
+	// should wait before moving on to the next server in the list, per
+	// server in the cluster. It bounds how often any one server sees a
+	// new connection so that a large fleet of clients doesn't overwhelm a
+	// small number of servers: the more servers there are to cycle
+	// through, the longer a full rebalance period takes.
+	rebalancePerServerInterval = 2 * time.Minute
+
+	// minRebalanceTimeout and maxRebalanceTimeout clamp the computed
+	// rebalance period so that small clusters don't rebalance too
+	// aggressively and large clusters don't go too long without
+	// reshuffling.
+	minRebalanceTimeout = 2 * time.Minute
+	maxRebalanceTimeout = 2 * time.Hour
+
+	// rebalanceTimeoutJitterFraction staggers the rebalance timer across
+	// the fleet so that clients don't all rebalance in lockstep.
+	rebalanceTimeoutJitterFraction = 0.25
+)
+
+// Pinger is implemented by the RPC layer and used by RebalanceServers to
+// probe the new head-of-list server after a shuffle, so that a server which
+// has stopped responding (but hasn't yet been reported failed by serf) is
+// demoted to the tail. FindServer does not ping; a server that stops
+// responding between rebalances is still returned until a caller reports
+// it with NotifyFailedServer.
+type Pinger interface {
+	Ping(addr net.Addr) error
+}
+
+// Server represents a single Nomad server discovered via serf membership.
+type Server struct {
+	Name       string
+	Region     string
+	Datacenter string
+	Addr       net.Addr
+	RPCAddr    net.Addr
+	NonVoter   bool
+}
+
+func (s *Server) String() string {
+	return s.Name
+}
+
+// serverList is an immutable snapshot of the known servers. Manager swaps
+// in a new serverList rather than mutating a shared slice, so readers never
+// observe a partially updated list.
+type serverList struct {
+	servers []*Server
+}
+
+// Manager tracks the set of Nomad servers known via serf, hands out a
+// healthy one to callers that need to make an RPC, and rotates away from
+// servers that stop responding. It is the RPC-forwarding analogue of the
+// client-side server manager: high-frequency dispatch/deploy traffic
+// spreads across healthy servers and survives a single server's death
+// without callers needing retry-with-shuffle logic of their own.
+type Manager struct {
+	// listValue holds the current *serverList and is swapped atomically so
+	// FindServer never blocks on the rebalance/notify paths.
+	listValue atomic.Value
+	listLock  sync.Mutex
+
+	logger *log.Logger
+
+	pinger Pinger
+
+	shutdownCh chan struct{}
+}
+
+// New creates a new Manager. shutdownCh is closed by the caller to stop the
+// background rebalance loop started by Run.
+func New(logger *log.Logger, shutdownCh chan struct{}, pinger Pinger) *Manager {
+	m := &Manager{
+		logger:     logger,
+		pinger:     pinger,
+		shutdownCh: shutdownCh,
+	}
+	m.saveServerList(serverList{})
+	return m
+}
+
+func (m *Manager) getServerList() serverList {
+	return m.listValue.Load().(serverList)
+}
+
+func (m *Manager) saveServerList(l serverList) {
+	m.listValue.Store(l)
+}
+
+// SetServers replaces the known server set, as reported by serf membership
+// events (a server joining, leaving, or changing its tags). The relative
+// order of servers that are present in both the old and new sets is
+// preserved so that an in-progress preference for a healthy head-of-list
+// server isn't disturbed by an unrelated join/leave elsewhere in the
+// cluster, but the server's fields always come from the new set - a tag
+// change such as NonVoter flipping, or an Addr/RPCAddr changing after a
+// restart, must be reflected even though the server kept the same name.
+func (m *Manager) SetServers(servers []*Server) {
+	m.listLock.Lock()
+	defer m.listLock.Unlock()
+
+	old := m.getServerList()
+	byName := make(map[string]*Server, len(servers))
+	for _, s := range servers {
+		byName[s.Name] = s
+	}
+
+	merged := make([]*Server, 0, len(servers))
+	for _, s := range old.servers {
+		if fresh, ok := byName[s.Name]; ok {
+			merged = append(merged, fresh)
+			delete(byName, s.Name)
+		}
+	}
+	for _, s := range servers {
+		if _, ok := byName[s.Name]; ok {
+			merged = append(merged, s)
+		}
+	}
+
+	m.saveServerList(serverList{servers: merged})
+}
+
+// FindServer returns the server that should be used for the next RPC, or
+// nil if no servers are known. It does not probe the server; callers that
+// want a liveness guarantee before use should pair this with
+// NotifyFailedServer on error.
+//
+// Non-voters are skipped in favor of the first voter in the list, since a
+// non-voting replica is a poor choice to forward a write to and shouldn't
+// count toward autopilot's voting quorum expectations. A non-voter is only
+// returned if the cluster has no voters at all.
+func (m *Manager) FindServer() *Server {
+	l := m.getServerList()
+	if len(l.servers) == 0 {
+		return nil
+	}
+
+	for _, s := range l.servers {
+		if !s.NonVoter {
+			return s
+		}
+	}
+
+	return l.servers[0]
+}
+
+// NumVoters returns the number of known servers that are participating in
+// the Raft voting quorum, i.e. excluding non-voting replicas. This is the
+// count autopilot's Expect setting should be compared against, rather than
+// NumServers.
+func (m *Manager) NumVoters() int {
+	voters := 0
+	for _, s := range m.getServerList().servers {
+		if !s.NonVoter {
+			voters++
+		}
+	}
+	return voters
+}
+
+// NotifyFailedServer is called by an RPC caller when a server returned by
+// FindServer failed to respond. The server is rotated to the tail of the
+// list so that subsequent callers prefer a different server, without
+// removing it outright - serf, not the caller, is authoritative on server
+// membership.
+//
+// s need not be at the head of the list: FindServer skips leading
+// non-voters, so the server an RPC was actually sent to, and that is being
+// reported failed here, can be anywhere in the list.
+func (m *Manager) NotifyFailedServer(s *Server) {
+	m.listLock.Lock()
+	defer m.listLock.Unlock()
+
+	l := m.getServerList()
+	if len(l.servers) < 2 {
+		return
+	}
+
+	idx := -1
+	for i, existing := range l.servers {
+		if existing == s {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return
+	}
+
+	servers := make([]*Server, 0, len(l.servers))
+	servers = append(servers, l.servers[:idx]...)
+	servers = append(servers, l.servers[idx+1:]...)
+	servers = append(servers, s)
+	m.saveServerList(serverList{servers: servers})
+}
+
+// RebalanceServers shuffles the known server list and pings the new
+// head-of-list server via the configured Pinger. If the new head doesn't
+// respond, it is demoted the same way NotifyFailedServer would demote it
+// and the next server in the shuffled order is probed instead. At most
+// len(servers) servers are probed - if every server in the list fails to
+// respond, the loop stops and the (fully rotated) shuffled order is kept
+// rather than spinning forever.
+func (m *Manager) RebalanceServers() {
+	m.listLock.Lock()
+	l := m.getServerList()
+	servers := make([]*Server, len(l.servers))
+	copy(servers, l.servers)
+	m.listLock.Unlock()
+
+	rand.Shuffle(len(servers), func(i, j int) {
+		servers[i], servers[j] = servers[j], servers[i]
+	})
+
+	if m.pinger != nil {
+		for attempts := 0; attempts < len(servers); attempts++ {
+			if err := m.pinger.Ping(servers[0].RPCAddr); err != nil {
+				m.logger.Printf("[DEBUG] servers: rebalance demoting unreachable server %s: %v", servers[0], err)
+				servers = append(servers[1:], servers[0])
+				continue
+			}
+			break
+		}
+	}
+
+	m.listLock.Lock()
+	m.saveServerList(serverList{servers: servers})
+	m.listLock.Unlock()
+}
+
+// NumServers returns the number of servers currently known to the manager.
+func (m *Manager) NumServers() int {
+	return len(m.getServerList().servers)
+}
+
+// connRebalanceTimeout computes how long the manager should wait before its
+// next rebalance, scaled by cluster size: each additional server adds
+// another rebalancePerServerInterval to the period, so a client cycles
+// through a larger cluster at roughly the same per-server rate instead of
+// hammering every server in lockstep. The result is clamped to
+// [minRebalanceTimeout, maxRebalanceTimeout] and jittered by
+// ±rebalanceTimeoutJitterFraction to avoid every client rebalancing at the
+// same moment.
+func connRebalanceTimeout(numServers int) time.Duration {
+	return jitter(connRebalanceTimeoutUnjittered(numServers))
+}
+
+// connRebalanceTimeoutUnjittered is connRebalanceTimeout without the final
+// ±rebalanceTimeoutJitterFraction jitter applied, split out so tests can
+// assert the clamping and cluster-size scaling deterministically.
+func connRebalanceTimeoutUnjittered(numServers int) time.Duration {
+	if numServers <= numServersRebalanceThreshold {
+		return clientRPCMinReuseDuration
+	}
+
+	timeout := time.Duration(numServers) * rebalancePerServerInterval
+
+	if timeout < minRebalanceTimeout {
+		timeout = minRebalanceTimeout
+	} else if timeout > maxRebalanceTimeout {
+		timeout = maxRebalanceTimeout
+	}
+
+	return timeout
+}
+
+// jitter returns d adjusted by a random amount within
+// ±rebalanceTimeoutJitterFraction.
+func jitter(d time.Duration) time.Duration {
+	jitter := time.Duration(rand.Float64()*2*rebalanceTimeoutJitterFraction*float64(d)) - time.Duration(rebalanceTimeoutJitterFraction*float64(d))
+	return d + jitter
+}
+
+// Run starts the background loop that periodically rebalances the server
+// list. It blocks until shutdownCh is closed, so callers should invoke it
+// in its own goroutine.
+func (m *Manager) Run() {
+	for {
+		timeout := connRebalanceTimeout(m.NumServers())
+		select {
+		case <-time.After(timeout):
+			m.RebalanceServers()
+		case <-m.shutdownCh:
+			return
+		}
+	}
+}
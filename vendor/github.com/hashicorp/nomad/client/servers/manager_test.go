@@ -0,0 +1,96 @@
+package servers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnRebalanceTimeoutUnjittered_GrowsWithClusterSize(t *testing.T) {
+	prev := time.Duration(0)
+	for _, n := range []int{4, 10, 50, 100} {
+		timeout := connRebalanceTimeoutUnjittered(n)
+		if timeout <= prev {
+			t.Fatalf("timeout for %d servers (%s) did not grow past the timeout for a smaller cluster (%s)", n, timeout, prev)
+		}
+		prev = timeout
+	}
+}
+
+func TestConnRebalanceTimeoutUnjittered_ClampedToBounds(t *testing.T) {
+	if got := connRebalanceTimeoutUnjittered(1); got != clientRPCMinReuseDuration {
+		t.Fatalf("expected small clusters to use clientRPCMinReuseDuration, got %s", got)
+	}
+
+	if got := connRebalanceTimeoutUnjittered(100000); got != maxRebalanceTimeout {
+		t.Fatalf("expected a huge cluster to clamp to maxRebalanceTimeout, got %s", got)
+	}
+}
+
+func TestManager_FindServer_SkipsNonVoters(t *testing.T) {
+	m := New(nil, make(chan struct{}), nil)
+	voter := &Server{Name: "voter"}
+	nonVoter := &Server{Name: "non-voter", NonVoter: true}
+	m.SetServers([]*Server{nonVoter, voter})
+
+	if got := m.FindServer(); got != voter {
+		t.Fatalf("expected FindServer to prefer the voter, got %v", got)
+	}
+
+	if got := m.NumVoters(); got != 1 {
+		t.Fatalf("expected 1 voter, got %d", got)
+	}
+}
+
+func TestManager_FindServer_FallsBackToNonVoter(t *testing.T) {
+	m := New(nil, make(chan struct{}), nil)
+	nonVoter := &Server{Name: "non-voter", NonVoter: true}
+	m.SetServers([]*Server{nonVoter})
+
+	if got := m.FindServer(); got != nonVoter {
+		t.Fatalf("expected FindServer to fall back to the only known server, got %v", got)
+	}
+
+	if got := m.NumVoters(); got != 0 {
+		t.Fatalf("expected 0 voters, got %d", got)
+	}
+}
+
+func TestManager_SetServers_RefreshesFieldsOnExistingEntry(t *testing.T) {
+	m := New(nil, make(chan struct{}), nil)
+	m.SetServers([]*Server{{Name: "a", NonVoter: false}})
+
+	if got := m.FindServer(); got == nil || got.NonVoter {
+		t.Fatalf("expected the initial server to be a voter, got %v", got)
+	}
+
+	m.SetServers([]*Server{{Name: "a", NonVoter: true}})
+
+	voters := m.NumVoters()
+	if voters != 0 {
+		t.Fatalf("expected the NonVoter flip to be picked up, still counted %d voters", voters)
+	}
+	if got := m.FindServer(); !got.NonVoter {
+		t.Fatalf("expected FindServer to return the updated (non-voter) server, got %v", got)
+	}
+}
+
+func TestManager_NotifyFailedServer_DemotesNonHeadVoter(t *testing.T) {
+	m := New(nil, make(chan struct{}), nil)
+	nonVoter := &Server{Name: "non-voter", NonVoter: true}
+	voterA := &Server{Name: "voter-a"}
+	voterB := &Server{Name: "voter-b"}
+
+	// A leading non-voter means FindServer's pick (voterA) isn't at the
+	// head of the underlying list.
+	m.SetServers([]*Server{nonVoter, voterA, voterB})
+
+	if got := m.FindServer(); got != voterA {
+		t.Fatalf("expected FindServer to return voterA, got %v", got)
+	}
+
+	m.NotifyFailedServer(voterA)
+
+	if got := m.FindServer(); got != voterB {
+		t.Fatalf("expected the failed voterA to be passed over in favor of voterB, got %v", got)
+	}
+}
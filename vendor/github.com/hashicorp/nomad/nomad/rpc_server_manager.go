@@ -0,0 +1,72 @@
+package nomad
+
+import (
+	"log"
+
+	"github.com/hashicorp/nomad/client/servers"
+	"github.com/hashicorp/serf/serf"
+)
+
+// serverPartsToServer adapts a serverParts, as produced by isNomadServer,
+// into the servers.Server representation consumed by the RPC-forwarding
+// Manager.
+func serverPartsToServer(parts *serverParts) *servers.Server {
+	return &servers.Server{
+		Name:       parts.Name,
+		Region:     parts.Region,
+		Datacenter: parts.Datacenter,
+		Addr:       parts.Addr,
+		RPCAddr:    parts.RPCAddr,
+		NonVoter:   parts.NonVoter,
+	}
+}
+
+// serversInRegion filters a serf membership snapshot down to the alive
+// Nomad servers belonging to region, converted to the servers.Server shape
+// the Manager expects. A server that serf has marked failed or left is
+// dropped from the forwarding pool until it rejoins, at which point the
+// next membership event adds it back via monitorServerMembership.
+func serversInRegion(members []serf.Member, region string) []*servers.Server {
+	var out []*servers.Server
+	for _, m := range members {
+		valid, parts := isNomadServer(m)
+		if !valid || parts.Region != region || parts.Status != serf.StatusAlive {
+			continue
+		}
+		out = append(out, serverPartsToServer(parts))
+	}
+	return out
+}
+
+// newServerManager builds an RPC-forwarding Manager with an empty server
+// list and starts its background rebalance loop; the list is filled for a
+// region by monitorServerMembership. It is the replacement for the old
+// pattern of shuffling a flat list of server names on every RPC: callers
+// route RPCs through Manager.FindServer, and a caller that gets a failed
+// RPC reports it with Manager.NotifyFailedServer so the next lookup
+// prefers a different server.
+func newServerManager(logger *log.Logger, shutdownCh chan struct{}, pinger servers.Pinger) *servers.Manager {
+	mgr := servers.New(logger, shutdownCh, pinger)
+	go mgr.Run()
+	return mgr
+}
+
+// monitorServerMembership consumes serf membership events - a server
+// joining, leaving, failing, or updating its tags - and refreshes mgr's
+// server list accordingly, so FindServer/NotifyFailedServer always reflect
+// current membership rather than the snapshot taken when the manager was
+// created. It runs until eventCh is closed or shutdownCh fires, and should
+// be started in its own goroutine alongside newServerManager.
+func monitorServerMembership(mgr *servers.Manager, region string, members func() []serf.Member, eventCh <-chan serf.Event, shutdownCh chan struct{}) {
+	for {
+		select {
+		case _, ok := <-eventCh:
+			if !ok {
+				return
+			}
+			mgr.SetServers(serversInRegion(members(), region))
+		case <-shutdownCh:
+			return
+		}
+	}
+}
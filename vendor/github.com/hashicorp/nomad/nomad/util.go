@@ -2,7 +2,6 @@ package nomad
 
 import (
 	"fmt"
-	"math/rand"
 	"net"
 	"os"
 	"path/filepath"
@@ -24,20 +23,19 @@ func ensurePath(path string, dir bool) error {
 
 // serverParts is used to return the parts of a server role
 type serverParts struct {
-	Name         string
-	ID           string
-	Region       string
-	Datacenter   string
-	Port         int
-	Bootstrap    bool
-	Expect       int
-	MajorVersion int
-	MinorVersion int
-	Build        version.Version
-	RaftVersion  int
-	Addr         net.Addr
-	RPCAddr      net.Addr
-	Status       serf.MemberStatus
+	Name        string
+	ID          string
+	Region      string
+	Datacenter  string
+	Port        int
+	Bootstrap   bool
+	Expect      int
+	Build       version.Version
+	RaftVersion int
+	Addr        net.Addr
+	RPCAddr     net.Addr
+	Status      serf.MemberStatus
+	NonVoter    bool
 }
 
 func (s *serverParts) String() string {
@@ -66,6 +64,12 @@ func isNomadServer(m serf.Member) (bool, *serverParts) {
 	datacenter := m.Tags["dc"]
 	_, bootstrap := m.Tags["bootstrap"]
 
+	// The "nonvoter" tag identifies servers that are present in serf but
+	// should be excluded from voting-related computations, such as
+	// autopilot's Expect count and the selection of a server to forward
+	// writes to.
+	_, nonVoter := m.Tags["nonvoter"]
+
 	expect := 0
 	expectStr, ok := m.Tags["expect"]
 	var err error
@@ -93,21 +97,6 @@ func isNomadServer(m serf.Member) (bool, *serverParts) {
 		return false, nil
 	}
 
-	// The "vsn" tag was Version, which is now the MajorVersion number.
-	majorVersionStr := m.Tags["vsn"]
-	majorVersion, err := strconv.Atoi(majorVersionStr)
-	if err != nil {
-		return false, nil
-	}
-
-	// To keep some semblance of convention, "mvn" is now the "Minor
-	// Version Number."
-	minorVersionStr := m.Tags["mvn"]
-	minorVersion, err := strconv.Atoi(minorVersionStr)
-	if err != nil {
-		minorVersion = 0
-	}
-
 	raftVsn := 0
 	raftVsnString, ok := m.Tags["raft_vsn"]
 	if ok {
@@ -120,35 +109,45 @@ func isNomadServer(m serf.Member) (bool, *serverParts) {
 	addr := &net.TCPAddr{IP: m.Addr, Port: port}
 	rpcAddr := &net.TCPAddr{IP: rpcIP, Port: port}
 	parts := &serverParts{
-		Name:         m.Name,
-		ID:           id,
-		Region:       region,
-		Datacenter:   datacenter,
-		Port:         port,
-		Bootstrap:    bootstrap,
-		Expect:       expect,
-		Addr:         addr,
-		RPCAddr:      rpcAddr,
-		MajorVersion: majorVersion,
-		MinorVersion: minorVersion,
-		Build:        *buildVersion,
-		RaftVersion:  raftVsn,
-		Status:       m.Status,
+		Name:        m.Name,
+		ID:          id,
+		Region:      region,
+		Datacenter:  datacenter,
+		Port:        port,
+		Bootstrap:   bootstrap,
+		Expect:      expect,
+		Addr:        addr,
+		RPCAddr:     rpcAddr,
+		Build:       *buildVersion,
+		RaftVersion: raftVsn,
+		Status:      m.Status,
+		NonVoter:    nonVoter,
 	}
 	return true, parts
 }
 
-// ServersMeetMinimumVersion returns whether the given alive servers are at least on the
-// given Nomad version
-func ServersMeetMinimumVersion(members []serf.Member, minVersion *version.Version) bool {
+// ServersMeetMinimumVersion returns whether the Nomad servers in the given
+// region are at least on the given Nomad version. Servers in other regions
+// are ignored, since a federated peer lagging behind should not block a
+// feature from being enabled locally. If checkFailedServers is true, servers
+// in serf.StatusFailed are considered in addition to serf.StatusAlive ones,
+// so that a partitioned-but-not-yet-reaped old server can't be missed.
+func ServersMeetMinimumVersion(members []serf.Member, region string, minVersion *version.Version, checkFailedServers bool) bool {
 	for _, member := range members {
-		if valid, parts := isNomadServer(member); valid && parts.Status == serf.StatusAlive {
-			// Check if the versions match - version.LessThan will return true for
-			// 0.8.0-rc1 < 0.8.0, so we want to ignore the metadata
-			versionsMatch := slicesMatch(minVersion.Segments(), parts.Build.Segments())
-			if parts.Build.LessThan(minVersion) && !versionsMatch {
-				return false
-			}
+		valid, parts := isNomadServer(member)
+		if !valid || parts.Region != region {
+			continue
+		}
+
+		if parts.Status != serf.StatusAlive && !(checkFailedServers && parts.Status == serf.StatusFailed) {
+			continue
+		}
+
+		// Check if the versions match - version.LessThan will return true for
+		// 0.8.0-rc1 < 0.8.0, so we want to ignore the metadata
+		versionsMatch := slicesMatch(minVersion.Segments(), parts.Build.Segments())
+		if parts.Build.LessThan(minVersion) && !versionsMatch {
+			return false
 		}
 	}
 
@@ -177,14 +176,6 @@ func slicesMatch(a, b []int) bool {
 	return true
 }
 
-// shuffleStrings randomly shuffles the list of strings
-func shuffleStrings(list []string) {
-	for i := range list {
-		j := rand.Intn(i + 1)
-		list[i], list[j] = list[j], list[i]
-	}
-}
-
 // maxUint64 returns the maximum value
 func maxUint64(inputs ...uint64) uint64 {
 	l := len(inputs)
@@ -216,17 +207,29 @@ func getNodeForRpc(snap *state.StateSnapshot, nodeID string) (*structs.Node, err
 		return nil, fmt.Errorf("Unknown node %q", nodeID)
 	}
 
-	if err := nodeSupportsRpc(node); err != nil {
+	if err := nodeSupportsFeature(node, "NodeRPC"); err != nil {
 		return nil, err
 	}
 
 	return node, nil
 }
 
-var minNodeVersionSupportingRPC = version.Must(version.NewVersion("0.8.0-rc1"))
+// featureMinVersions is the canonical table of capability floors. Adding a
+// new capability gate means adding an entry here, rather than sprinkling
+// another minFooVersion var through the package.
+var featureMinVersions = map[string]*version.Version{
+	"NodeRPC": version.Must(version.NewVersion("0.8.0-rc1")),
+}
+
+// nodeSupportsFeature returns a non-nil error if the Node's reported
+// version does not meet the minimum version registered for the given
+// feature in featureMinVersions.
+func nodeSupportsFeature(node *structs.Node, feature string) error {
+	minVersion, ok := featureMinVersions[feature]
+	if !ok {
+		return fmt.Errorf("unknown feature %q", feature)
+	}
 
-// nodeSupportsRpc returns a non-nil error if a Node does not support RPC.
-func nodeSupportsRpc(node *structs.Node) error {
 	rawNodeVer, ok := node.Attributes["nomad.version"]
 	if !ok {
 		return structs.ErrUnknownNomadVersion
@@ -237,7 +240,7 @@ func nodeSupportsRpc(node *structs.Node) error {
 		return structs.ErrUnknownNomadVersion
 	}
 
-	if nodeVer.LessThan(minNodeVersionSupportingRPC) {
+	if nodeVer.LessThan(minVersion) {
 		return structs.ErrNodeLacksRpc
 	}
 
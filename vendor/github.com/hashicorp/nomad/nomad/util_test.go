@@ -0,0 +1,85 @@
+package nomad
+
+import (
+	"net"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/serf/serf"
+)
+
+func testServerMember(t *testing.T, name, region string, build string, status serf.MemberStatus) serf.Member {
+	t.Helper()
+	return serf.Member{
+		Name: name,
+		Addr: net.ParseIP("127.0.0.1"),
+		Tags: map[string]string{
+			"role":   "nomad",
+			"region": region,
+			"dc":     "dc1",
+			"build":  build,
+			"port":   "4647",
+		},
+		Status: status,
+	}
+}
+
+func TestServersMeetMinimumVersion_ScopedToRegion(t *testing.T) {
+	members := []serf.Member{
+		testServerMember(t, "old-east-1", "east", "0.7.0", serf.StatusAlive),
+		testServerMember(t, "new-west-1", "west", "0.8.0", serf.StatusAlive),
+		testServerMember(t, "new-west-2", "west", "0.8.0", serf.StatusAlive),
+	}
+
+	minVersion := version.Must(version.NewVersion("0.8.0"))
+
+	if ServersMeetMinimumVersion(members, "west", minVersion, false) != true {
+		t.Fatalf("expected the west region to meet the minimum version despite the lagging east server")
+	}
+
+	if ServersMeetMinimumVersion(members, "east", minVersion, false) != false {
+		t.Fatalf("expected the east region to fail the minimum version check")
+	}
+}
+
+func TestServersMeetMinimumVersion_CheckFailedServers(t *testing.T) {
+	members := []serf.Member{
+		testServerMember(t, "new-1", "east", "0.8.0", serf.StatusAlive),
+		testServerMember(t, "old-failed-1", "east", "0.7.0", serf.StatusFailed),
+	}
+
+	minVersion := version.Must(version.NewVersion("0.8.0"))
+
+	if ServersMeetMinimumVersion(members, "east", minVersion, false) != true {
+		t.Fatalf("expected a failed old server to be ignored when checkFailedServers is false")
+	}
+
+	if ServersMeetMinimumVersion(members, "east", minVersion, true) != false {
+		t.Fatalf("expected a failed old server to still block the version check when checkFailedServers is true")
+	}
+}
+
+func TestIsNomadServer_NonVoter(t *testing.T) {
+	m := testServerMember(t, "nonvoter-1", "east", "0.8.0", serf.StatusAlive)
+	m.Tags["nonvoter"] = "1"
+
+	valid, parts := isNomadServer(m)
+	if !valid {
+		t.Fatalf("expected %v to be recognized as a Nomad server", m)
+	}
+	if !parts.NonVoter {
+		t.Fatalf("expected NonVoter to be true for a member carrying the nonvoter tag")
+	}
+}
+
+func TestIsNomadServer_Voter(t *testing.T) {
+	m := testServerMember(t, "voter-1", "east", "0.8.0", serf.StatusAlive)
+
+	valid, parts := isNomadServer(m)
+	if !valid {
+		t.Fatalf("expected %v to be recognized as a Nomad server", m)
+	}
+	if parts.NonVoter {
+		t.Fatalf("expected NonVoter to be false for a member without the nonvoter tag")
+	}
+}